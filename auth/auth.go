@@ -0,0 +1,66 @@
+// Package auth provides Vault authentication methods suitable for
+// production use (AppRole, Kubernetes, JWT/OIDC) and a Client wrapper
+// that keeps the resulting token renewed for as long as the process
+// runs, re-authenticating from scratch when it can no longer be
+// renewed.
+package auth
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// Authenticator logs in to a Vault auth method and returns the
+// resulting auth secret (which carries the client token and its lease
+// metadata).
+type Authenticator interface {
+	Login(client *api.Client) (*api.Secret, error)
+}
+
+// AppRole authenticates using the AppRole auth method's role_id and
+// secret_id pair.
+type AppRole struct {
+	// Mount is the path the approle auth method is mounted at. Defaults
+	// to "approle".
+	Mount string
+	// RoleID is the AppRole's role ID. It isn't sensitive and is often
+	// baked into configuration.
+	RoleID string
+	// SecretID is the AppRole's secret ID. Prefer SecretIDFile so the
+	// value isn't held in configuration alongside RoleID.
+	SecretID string
+	// SecretIDFile, if set and SecretID is empty, is read for the
+	// secret ID on every Login call, so a wrapped/rotated secret ID can
+	// be refreshed on disk without restarting the process.
+	SecretIDFile string
+}
+
+// Login implements Authenticator.
+func (a AppRole) Login(client *api.Client) (*api.Secret, error) {
+	secretID := a.SecretID
+	if secretID == "" {
+		if a.SecretIDFile == "" {
+			return nil, fmt.Errorf("auth: AppRole requires SecretID or SecretIDFile")
+		}
+		b, err := readTrimmedFile(a.SecretIDFile)
+		if err != nil {
+			return nil, fmt.Errorf("auth: reading secret ID file: %w", err)
+		}
+		secretID = b
+	}
+
+	mount := a.Mount
+	if mount == "" {
+		mount = "approle"
+	}
+
+	secret, err := client.Logical().Write(mount+"/login", map[string]interface{}{
+		"role_id":   a.RoleID,
+		"secret_id": secretID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("auth: AppRole login: %w", err)
+	}
+	return secret, nil
+}
@@ -0,0 +1,241 @@
+package auth_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/ory/dockertest"
+	"github.com/ory/dockertest/docker"
+	jose "gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+
+	"github.com/johanbrandhorst/vaulter/auth"
+)
+
+func TestAuth(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Auth Suite")
+}
+
+var (
+	pool     *dockertest.Pool
+	resource *dockertest.Resource
+
+	rootClient *api.Client
+	vaultAddr  string
+
+	jwtKey *rsa.PrivateKey
+)
+
+var _ = BeforeSuite(func() {
+	host := "localhost"
+	if os.Getenv("DOCKER_HOST") != "" {
+		u, err := url.Parse(os.Getenv("DOCKER_HOST"))
+		Expect(err).To(Succeed())
+		host, _, err = net.SplitHostPort(u.Host)
+		Expect(err).To(Succeed())
+	}
+
+	var err error
+	pool, err = dockertest.NewPool("")
+	Expect(err).To(Succeed())
+	pool.MaxWait = time.Second * 10
+
+	By("Starting the Vault container", func() {
+		token := "mysecrettoken"
+
+		// Local JWT/Kubernetes validation via jwt_validation_pubkeys and
+		// pem_keys needs a Vault newer than the pki suite's pinned 1.0.0.
+		repo := "vault"
+		version := "1.13.3"
+		img := repo + ":" + version
+		_, err = pool.Client.InspectImage(img)
+		if err != nil {
+			Expect(pool.Client.PullImage(docker.PullImageOptions{
+				Repository:   repo,
+				Tag:          version,
+				OutputStream: GinkgoWriter,
+			}, docker.AuthConfiguration{})).To(Succeed())
+		}
+
+		resource, err = pool.RunWithOptions(&dockertest.RunOptions{
+			Repository:   repo,
+			Tag:          version,
+			Env:          []string{"VAULT_DEV_ROOT_TOKEN_ID=" + token},
+			ExposedPorts: []string{"8200/tcp"},
+		})
+		Expect(err).To(Succeed())
+
+		vaultAddr = "http://" + net.JoinHostPort(host, resource.GetPort("8200/tcp"))
+		conf := api.DefaultConfig()
+		conf.Address = vaultAddr
+		rootClient, err = api.NewClient(conf)
+		Expect(err).To(Succeed())
+		rootClient.SetToken(token)
+
+		Expect(pool.Retry(func() error {
+			_, err := rootClient.Sys().Health()
+			return err
+		})).To(Succeed())
+	})
+
+	By("Generating the JWT/Kubernetes signing key", func() {
+		var err error
+		jwtKey, err = rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).To(Succeed())
+	})
+})
+
+var _ = AfterSuite(func() {
+	if resource != nil {
+		Expect(pool.Purge(resource)).To(Succeed())
+	}
+})
+
+func pubKeyPEM(key *rsa.PrivateKey) string {
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	Expect(err).To(Succeed())
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func signJWT(key *rsa.PrivateKey, claims jwt.Claims) string {
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key}, nil)
+	Expect(err).To(Succeed())
+	token, err := jwt.Signed(signer).Claims(claims).CompactSerialize()
+	Expect(err).To(Succeed())
+	return token
+}
+
+func newRootScopedClient() *api.Client {
+	conf := api.DefaultConfig()
+	conf.Address = vaultAddr
+	c, err := api.NewClient(conf)
+	Expect(err).To(Succeed())
+	return c
+}
+
+var _ = Describe("AppRole", func() {
+	It("keeps a client authenticated across a forced token expiration", func() {
+		Expect(rootClient.Sys().EnableAuthWithOptions("approle", &api.EnableAuthOptions{Type: "approle"})).To(Succeed())
+
+		_, err := rootClient.Logical().Write("auth/approle/role/test-role", map[string]interface{}{
+			"token_ttl":      "2s",
+			"token_max_ttl":  "2s",
+			"token_policies": []string{"default"},
+		})
+		Expect(err).To(Succeed())
+
+		roleID, err := rootClient.Logical().Read("auth/approle/role/test-role/role-id")
+		Expect(err).To(Succeed())
+		secretID, err := rootClient.Logical().Write("auth/approle/role/test-role/secret-id", nil)
+		Expect(err).To(Succeed())
+
+		authenticator := auth.AppRole{
+			RoleID:   roleID.Data["role_id"].(string),
+			SecretID: secretID.Data["secret_id"].(string),
+		}
+
+		var reauthed atomic.Bool
+		client, err := auth.NewClient(newRootScopedClient(), authenticator, auth.ClientConfig{
+			OnReauth: func(*api.Secret) { reauthed.Store(true) },
+		})
+		Expect(err).To(Succeed())
+		defer client.Close()
+
+		firstToken := client.Client.Token()
+		Expect(firstToken).ToNot(BeEmpty())
+
+		// token_max_ttl is 2s, well under the 5s+ this repo's other
+		// suites already tolerate for container round trips, so the
+		// renew loop is forced to re-authenticate at least once.
+		Eventually(func() bool {
+			_, err := client.Client.Auth().Token().LookupSelf()
+			return err == nil && reauthed.Load()
+		}, "10s", "200ms").Should(BeTrue())
+	})
+})
+
+var _ = Describe("JWT", func() {
+	It("authenticates using a signed JWT", func() {
+		Expect(rootClient.Sys().EnableAuthWithOptions("jwt", &api.EnableAuthOptions{Type: "jwt"})).To(Succeed())
+
+		_, err := rootClient.Logical().Write("auth/jwt/config", map[string]interface{}{
+			"jwt_validation_pubkeys": []string{pubKeyPEM(jwtKey)},
+		})
+		Expect(err).To(Succeed())
+
+		_, err = rootClient.Logical().Write("auth/jwt/role/test-role", map[string]interface{}{
+			"role_type":       "jwt",
+			"bound_audiences": []string{"vaulter-tests"},
+			"user_claim":      "sub",
+			"token_policies":  []string{"default"},
+			"token_ttl":       "1m",
+		})
+		Expect(err).To(Succeed())
+
+		token := signJWT(jwtKey, jwt.Claims{
+			Subject:  "vaulter-test-user",
+			Audience: jwt.Audience{"vaulter-tests"},
+			Expiry:   jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		})
+
+		client, err := auth.NewClient(newRootScopedClient(), auth.JWT{Role: "test-role", Token: token}, auth.ClientConfig{})
+		Expect(err).To(Succeed())
+		defer client.Close()
+
+		Expect(client.Client.Token()).ToNot(BeEmpty())
+	})
+})
+
+var _ = Describe("Kubernetes", func() {
+	It("authenticates using a locally-validated service account JWT", func() {
+		Expect(rootClient.Sys().EnableAuthWithOptions("kubernetes", &api.EnableAuthOptions{Type: "kubernetes"})).To(Succeed())
+
+		_, err := rootClient.Logical().Write("auth/kubernetes/config", map[string]interface{}{
+			"pem_keys":               []string{pubKeyPEM(jwtKey)},
+			"kubernetes_host":        "https://localhost:6443",
+			"disable_iss_validation": true,
+		})
+		Expect(err).To(Succeed())
+
+		_, err = rootClient.Logical().Write("auth/kubernetes/role/test-role", map[string]interface{}{
+			"bound_service_account_names":      []string{"*"},
+			"bound_service_account_namespaces": []string{"*"},
+			"token_policies":                   []string{"default"},
+			"token_ttl":                        "1m",
+		})
+		Expect(err).To(Succeed())
+
+		token := signJWT(jwtKey, jwt.Claims{
+			Subject: "system:serviceaccount:default:vaulter-test",
+			Expiry:  jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		})
+
+		tokenFile, err := os.CreateTemp("", "sa-token")
+		Expect(err).To(Succeed())
+		defer os.Remove(tokenFile.Name())
+		_, err = tokenFile.WriteString(token)
+		Expect(err).To(Succeed())
+		Expect(tokenFile.Close()).To(Succeed())
+
+		client, err := auth.NewClient(newRootScopedClient(), auth.Kubernetes{
+			Role:      "test-role",
+			TokenPath: tokenFile.Name(),
+		}, auth.ClientConfig{})
+		Expect(err).To(Succeed())
+		defer client.Close()
+
+		Expect(client.Client.Token()).ToNot(BeEmpty())
+	})
+})
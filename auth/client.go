@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// DefaultRenewFraction is the fraction of a token's TTL at which
+// Client renews it, if Client.RenewFraction is zero.
+const DefaultRenewFraction = 2.0 / 3.0
+
+// ClientConfig configures optional Client behavior. The zero value
+// selects DefaultRenewFraction and installs no callbacks.
+type ClientConfig struct {
+	// RenewFraction is the fraction of a token's TTL at which it is
+	// renewed. Defaults to DefaultRenewFraction.
+	RenewFraction float64
+	// OnRenew, if set, is called after every successful RenewSelf.
+	OnRenew func(*api.Secret)
+	// OnReauth, if set, is called after every successful re-
+	// authentication via Authenticator.
+	OnReauth func(*api.Secret)
+}
+
+// Client wraps an *api.Client, keeping it authenticated for as long as
+// the process runs: it renews the current token at RenewFraction of its
+// TTL, and transparently re-authenticates from scratch via Authenticator
+// once the token becomes non-renewable or hits its max TTL.
+type Client struct {
+	*api.Client
+
+	// RenewFraction, OnRenew and OnReauth mirror the fields of the
+	// ClientConfig passed to NewClient. They're read by the background
+	// renewal goroutine without synchronization, so must not be mutated
+	// after construction.
+	RenewFraction float64
+	OnRenew       func(*api.Secret)
+	OnReauth      func(*api.Secret)
+
+	authenticator Authenticator
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewClient logs in to Vault via authenticator, sets the resulting
+// token on client, and starts a background goroutine that keeps it
+// renewed. Call Close to stop the goroutine.
+func NewClient(client *api.Client, authenticator Authenticator, cfg ClientConfig) (*Client, error) {
+	c := &Client{
+		Client:        client,
+		authenticator: authenticator,
+		RenewFraction: cfg.RenewFraction,
+		OnRenew:       cfg.OnRenew,
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+
+	if err := c.reauthenticate(); err != nil {
+		return nil, err
+	}
+
+	// The initial login isn't a re-authentication, so OnReauth is only
+	// installed once it has succeeded.
+	c.OnReauth = cfg.OnReauth
+
+	go c.renewLoop()
+
+	return c, nil
+}
+
+// Close stops the background renewal goroutine.
+func (c *Client) Close() error {
+	close(c.stop)
+	<-c.done
+	return nil
+}
+
+func (c *Client) reauthenticate() error {
+	secret, err := c.authenticator.Login(c.Client)
+	if err != nil {
+		return fmt.Errorf("auth: authenticating: %w", err)
+	}
+	if secret == nil || secret.Auth == nil {
+		return fmt.Errorf("auth: login returned no auth info")
+	}
+
+	c.Client.SetToken(secret.Auth.ClientToken)
+
+	if c.OnReauth != nil {
+		c.OnReauth(secret)
+	}
+
+	return nil
+}
+
+func (c *Client) renewLoop() {
+	defer close(c.done)
+
+	for {
+		wait, renewable, err := c.timeToRenew()
+		if err != nil {
+			wait = DefaultRetryInterval
+			renewable = false
+		}
+
+		select {
+		case <-c.stop:
+			return
+		case <-time.After(wait):
+		}
+
+		if err == nil && renewable {
+			if err := c.renew(); err == nil {
+				continue
+			}
+		}
+
+		// Either the token can't be renewed any further, or renewal
+		// failed outright (e.g. it was revoked out of band). Start
+		// over from a clean login.
+		if err := c.reauthenticate(); err != nil {
+			// Back off before trying again rather than hammering
+			// Vault in a tight loop.
+			select {
+			case <-c.stop:
+				return
+			case <-time.After(jitter(DefaultRetryInterval)):
+			}
+		}
+	}
+}
+
+// timeToRenew looks up the current token's TTL via LookupSelf and
+// returns how long to wait before the next renewal attempt, and whether
+// the token reports itself as renewable.
+func (c *Client) timeToRenew() (time.Duration, bool, error) {
+	secret, err := c.Client.Auth().Token().LookupSelf()
+	if err != nil {
+		return 0, false, fmt.Errorf("auth: looking up token: %w", err)
+	}
+
+	ttl, _ := secret.Data["ttl"].(float64)
+	renewable, _ := secret.Data["renewable"].(bool)
+
+	fraction := c.RenewFraction
+	if fraction <= 0 {
+		fraction = DefaultRenewFraction
+	}
+
+	wait := time.Duration(ttl * fraction * float64(time.Second))
+	if wait <= 0 {
+		wait = time.Second
+	}
+	return wait, renewable, nil
+}
+
+func (c *Client) renew() error {
+	secret, err := c.Client.Auth().Token().RenewSelf(0)
+	if err != nil {
+		return fmt.Errorf("auth: renewing token: %w", err)
+	}
+	if c.OnRenew != nil {
+		c.OnRenew(secret)
+	}
+	return nil
+}
+
+// DefaultRetryInterval is how long the renewal goroutine waits after a
+// failed token lookup, and the base of the jittered wait (between half
+// and one and a half times it) after a failed re-authentication. A
+// failed renewal is followed by an immediate re-authentication.
+const DefaultRetryInterval = 5 * time.Second
+
+func jitter(d time.Duration) time.Duration {
+	return d/2 + time.Duration(rand.Int63n(int64(d)))
+}
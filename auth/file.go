@@ -0,0 +1,14 @@
+package auth
+
+import (
+	"io/ioutil"
+	"strings"
+)
+
+func readTrimmedFile(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
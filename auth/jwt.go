@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// JWT authenticates using Vault's JWT/OIDC auth method's JWT login
+// flow (the OIDC browser flow has no unattended equivalent, so it isn't
+// offered here).
+type JWT struct {
+	// Mount is the path the jwt auth method is mounted at. Defaults to
+	// "jwt".
+	Mount string
+	// Role is the Vault role to authenticate as.
+	Role string
+	// Token is the signed JWT to present. Takes precedence over
+	// TokenPath.
+	Token string
+	// TokenPath, if set and Token is empty, is read for the JWT on
+	// every Login call.
+	TokenPath string
+}
+
+// Login implements Authenticator.
+func (j JWT) Login(client *api.Client) (*api.Secret, error) {
+	token := j.Token
+	if token == "" {
+		if j.TokenPath == "" {
+			return nil, fmt.Errorf("auth: JWT requires Token or TokenPath")
+		}
+		t, err := readTrimmedFile(j.TokenPath)
+		if err != nil {
+			return nil, fmt.Errorf("auth: reading JWT file: %w", err)
+		}
+		token = t
+	}
+
+	mount := j.Mount
+	if mount == "" {
+		mount = "jwt"
+	}
+
+	secret, err := client.Logical().Write(mount+"/login", map[string]interface{}{
+		"role": j.Role,
+		"jwt":  token,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("auth: JWT login: %w", err)
+	}
+	return secret, nil
+}
@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// DefaultServiceAccountTokenPath is where Kubernetes projects a pod's
+// service account token by default.
+const DefaultServiceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// Kubernetes authenticates using the Kubernetes auth method, presenting
+// the pod's projected service account JWT to Vault for validation
+// against the TokenReview API.
+type Kubernetes struct {
+	// Mount is the path the kubernetes auth method is mounted at.
+	// Defaults to "kubernetes".
+	Mount string
+	// Role is the Vault role to authenticate as.
+	Role string
+	// TokenPath is read for the service account JWT on every Login
+	// call, so a projected token refreshed by the kubelet is always
+	// picked up. Defaults to DefaultServiceAccountTokenPath.
+	TokenPath string
+}
+
+// Login implements Authenticator.
+func (k Kubernetes) Login(client *api.Client) (*api.Secret, error) {
+	tokenPath := k.TokenPath
+	if tokenPath == "" {
+		tokenPath = DefaultServiceAccountTokenPath
+	}
+	jwt, err := readTrimmedFile(tokenPath)
+	if err != nil {
+		return nil, fmt.Errorf("auth: reading service account token: %w", err)
+	}
+
+	mount := k.Mount
+	if mount == "" {
+		mount = "kubernetes"
+	}
+
+	secret, err := client.Logical().Write(mount+"/login", map[string]interface{}{
+		"role": k.Role,
+		"jwt":  jwt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("auth: Kubernetes login: %w", err)
+	}
+	return secret, nil
+}
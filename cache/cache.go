@@ -0,0 +1,189 @@
+// Package cache implements a crypto/tls.Config certificate source
+// modeled on golang.org/x/crypto/acme/autocert: certificates are issued
+// on demand by SNI name, persisted to a pluggable backing store, and
+// proactively renewed in the background well before they expire.
+package cache
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/johanbrandhorst/vaulter"
+)
+
+// ErrCacheMiss is returned by a Cache's Get method when no entry exists
+// for the given key.
+var ErrCacheMiss = errors.New("cache: certificate cache miss")
+
+// Cache stores and retrieves opaque, PEM-encoded certificate/key pairs
+// keyed by SNI or client identity name. Implementations must be safe for
+// concurrent use.
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Put(ctx context.Context, key string, data []byte) error
+	Delete(ctx context.Context, key string) error
+}
+
+// DefaultRenewBefore is the fraction of a certificate's lifetime that
+// must elapse before Manager proactively renews it, matching the
+// 2/3 default used against the fixture's defaultTTL=168h/maxTTL=720h
+// Vault role.
+const DefaultRenewBefore = 2.0 / 3.0
+
+// DefaultRetryInterval is the initial base of the jittered backoff
+// between renewal attempts after a failure. Each wait is between half
+// and one and a half times the base, and the base doubles after every
+// failed attempt.
+const DefaultRetryInterval = 30 * time.Second
+
+// Manager issues and caches certificates from a vaulter.Issuer, serving
+// them through the crypto/tls.Config.GetCertificate and
+// GetClientCertificate hooks.
+type Manager struct {
+	// Issuer issues and renews certificates. Any backend implementing
+	// vaulter.Issuer works: Vault PKI, ACME, step-ca, or a mix picked
+	// per Manager.
+	Issuer vaulter.Issuer
+	// Cache persists issued certificates across restarts. Defaults to an
+	// in-memory cache if nil.
+	Cache Cache
+	// RenewBefore is the fraction of a certificate's lifetime (0, 1)
+	// after which it is proactively renewed. Defaults to
+	// DefaultRenewBefore.
+	RenewBefore float64
+	// ClientIdentity is the common name requested for client
+	// certificates returned by GetClientCertificate. TLS gives the
+	// server no way to tell a client which identity it wants, so this
+	// must be configured up front.
+	ClientIdentity string
+
+	once sync.Once
+	sf   singleflight.Group
+}
+
+func (m *Manager) init() {
+	m.once.Do(func() {
+		if m.Cache == nil {
+			m.Cache = NewMemCache()
+		}
+		if m.RenewBefore <= 0 {
+			m.RenewBefore = DefaultRenewBefore
+		}
+	})
+}
+
+// GetCertificate implements crypto/tls.Config.GetCertificate, issuing or
+// returning a cached certificate for the requested SNI name.
+func (m *Manager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	m.init()
+	name := hello.ServerName
+	if name == "" {
+		return nil, fmt.Errorf("cache: no SNI server name in ClientHello")
+	}
+	return m.certificateFor(context.Background(), name, vaulter.CertRequest{
+		CommonName: name,
+		DNSNames:   []string{name},
+	})
+}
+
+// GetClientCertificate implements crypto/tls.Config.GetClientCertificate,
+// issuing or returning a cached certificate for Manager.ClientIdentity.
+func (m *Manager) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	m.init()
+	if m.ClientIdentity == "" {
+		return nil, fmt.Errorf("cache: no ClientIdentity configured")
+	}
+	return m.certificateFor(context.Background(), m.ClientIdentity, vaulter.CertRequest{
+		CommonName: m.ClientIdentity,
+	})
+}
+
+func (m *Manager) certificateFor(ctx context.Context, key string, req vaulter.CertRequest) (*tls.Certificate, error) {
+	if entry, err := m.loadFromCache(ctx, key); err == nil {
+		m.maybeRenewInBackground(key, req, entry)
+		return entry.tlsCertificate(), nil
+	} else if err != ErrCacheMiss {
+		return nil, err
+	}
+
+	v, err, _ := m.sf.Do(key, func() (interface{}, error) {
+		return m.issueAndStore(ctx, key, req)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*certEntry).tlsCertificate(), nil
+}
+
+func (m *Manager) issueAndStore(ctx context.Context, key string, req vaulter.CertRequest) (*certEntry, error) {
+	cert, err := m.Issuer.Issue(req)
+	if err != nil {
+		return nil, fmt.Errorf("cache: issuing certificate for %q: %w", key, err)
+	}
+	entry, err := newCertEntry(cert)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.Cache.Put(ctx, key, entry.encode()); err != nil {
+		return nil, fmt.Errorf("cache: storing certificate for %q: %w", key, err)
+	}
+	return entry, nil
+}
+
+func (m *Manager) loadFromCache(ctx context.Context, key string) (*certEntry, error) {
+	data, err := m.Cache.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return decodeCertEntry(data)
+}
+
+// maybeRenewInBackground kicks off a renewal once entry has crossed
+// RenewBefore of its lifetime. Renewals are deduplicated per key via
+// the Manager's singleflight group, so concurrent handshakes for the
+// same name never trigger more than one Issuer.Renew call.
+func (m *Manager) maybeRenewInBackground(key string, req vaulter.CertRequest, entry *certEntry) {
+	if !entry.needsRenewal(m.RenewBefore) {
+		return
+	}
+	go func() {
+		_, _, _ = m.sf.Do(key, func() (interface{}, error) {
+			return m.renewWithRetry(key, req)
+		})
+	}()
+}
+
+func (m *Manager) renewWithRetry(key string, req vaulter.CertRequest) (*certEntry, error) {
+	ctx := context.Background()
+	backoff := DefaultRetryInterval
+
+	for attempt := 0; attempt < 5; attempt++ {
+		cert, err := m.Issuer.Renew(req)
+		if err == nil {
+			entry, err := newCertEntry(cert)
+			if err != nil {
+				return nil, err
+			}
+			if err := m.Cache.Put(ctx, key, entry.encode()); err != nil {
+				return nil, err
+			}
+			return entry, nil
+		}
+
+		time.Sleep(jitter(backoff))
+		backoff *= 2
+	}
+
+	return nil, fmt.Errorf("cache: giving up renewing %q after repeated failures", key)
+}
+
+func jitter(d time.Duration) time.Duration {
+	return d/2 + time.Duration(rand.Int63n(int64(d)))
+}
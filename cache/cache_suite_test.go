@@ -0,0 +1,184 @@
+package cache_test
+
+import (
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/ory/dockertest"
+	"github.com/ory/dockertest/docker"
+
+	"github.com/johanbrandhorst/vaulter/cache"
+	"github.com/johanbrandhorst/vaulter/issuers/vault"
+)
+
+func TestCache(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Cache Suite")
+}
+
+const role = "test"
+
+var (
+	pool     *dockertest.Pool
+	resource *dockertest.Resource
+
+	vaultClient *api.Client
+	issuer      *vault.Issuer
+)
+
+var _ = BeforeSuite(func() {
+	host := "localhost"
+	if os.Getenv("DOCKER_HOST") != "" {
+		u, err := url.Parse(os.Getenv("DOCKER_HOST"))
+		Expect(err).To(Succeed())
+		host, _, err = net.SplitHostPort(u.Host)
+		Expect(err).To(Succeed())
+	}
+
+	var err error
+	pool, err = dockertest.NewPool("")
+	Expect(err).To(Succeed())
+	pool.MaxWait = time.Second * 10
+
+	By("Starting the Vault container", func() {
+		token := "mysecrettoken"
+
+		repo := "vault"
+		version := "1.0.0"
+		img := repo + ":" + version
+		_, err = pool.Client.InspectImage(img)
+		if err != nil {
+			Expect(pool.Client.PullImage(docker.PullImageOptions{
+				Repository:   repo,
+				Tag:          version,
+				OutputStream: GinkgoWriter,
+			}, docker.AuthConfiguration{})).To(Succeed())
+		}
+
+		resource, err = pool.RunWithOptions(&dockertest.RunOptions{
+			Repository:   repo,
+			Tag:          version,
+			Env:          []string{"VAULT_DEV_ROOT_TOKEN_ID=" + token},
+			ExposedPorts: []string{"8200/tcp"},
+		})
+		Expect(err).To(Succeed())
+
+		conf := api.DefaultConfig()
+		conf.Address = "http://" + net.JoinHostPort(host, resource.GetPort("8200/tcp"))
+		vaultClient, err = api.NewClient(conf)
+		Expect(err).To(Succeed())
+		vaultClient.SetToken(token)
+
+		Expect(pool.Retry(func() error {
+			_, err := vaultClient.Sys().Health()
+			return err
+		})).To(Succeed())
+
+		Expect(vaultClient.Sys().Mount("pki", &api.MountInput{
+			Type:   "pki",
+			Config: api.MountConfigInput{MaxLeaseTTL: "1h"},
+		})).To(Succeed())
+		_, err = vaultClient.Logical().Write("pki/root/generate/internal", map[string]interface{}{
+			"ttl":         "1h",
+			"common_name": "cache-suite",
+		})
+		Expect(err).To(Succeed())
+		_, err = vaultClient.Logical().Write("pki/roles/"+role, map[string]interface{}{
+			"allowed_domains":  "myserver.com",
+			"allow_subdomains": true,
+			"allow_any_name":   true,
+			"ttl":              "3s",
+			"max_ttl":          "3s",
+		})
+		Expect(err).To(Succeed())
+
+		Expect(vaultClient.Sys().Mount("secret", &api.MountInput{
+			Type: "kv-v2",
+		})).To(Succeed())
+
+		issuer, err = vault.New(vault.Config{Client: vaultClient, Mount: "pki", Role: role})
+		Expect(err).To(Succeed())
+	})
+})
+
+var _ = AfterSuite(func() {
+	if resource != nil {
+		Expect(pool.Purge(resource)).To(Succeed())
+	}
+})
+
+var _ = Describe("MemCache", func() {
+	It("round-trips a value", func() {
+		c := cache.NewMemCache()
+		Expect(c.Put(context.Background(), "k", []byte("v"))).To(Succeed())
+		got, err := c.Get(context.Background(), "k")
+		Expect(err).To(Succeed())
+		Expect(got).To(Equal([]byte("v")))
+	})
+
+	It("reports ErrCacheMiss for an unknown key", func() {
+		c := cache.NewMemCache()
+		_, err := c.Get(context.Background(), "missing")
+		Expect(err).To(Equal(cache.ErrCacheMiss))
+	})
+})
+
+var _ = Describe("DirCache", func() {
+	It("round-trips a value across instances", func() {
+		dir, err := ioutil.TempDir("", "dircache")
+		Expect(err).To(Succeed())
+		c := cache.DirCache(dir)
+		Expect(c.Put(context.Background(), "k", []byte("v"))).To(Succeed())
+
+		c2 := cache.DirCache(dir)
+		got, err := c2.Get(context.Background(), "k")
+		Expect(err).To(Succeed())
+		Expect(got).To(Equal([]byte("v")))
+	})
+})
+
+var _ = Describe("VaultCache", func() {
+	It("round-trips a value through the kv-v2 mount", func() {
+		c := &cache.VaultCache{Client: vaultClient, Mount: "secret", Prefix: "vaulter-test"}
+		Expect(c.Put(context.Background(), "k", []byte("hello vault"))).To(Succeed())
+		got, err := c.Get(context.Background(), "k")
+		Expect(err).To(Succeed())
+		Expect(got).To(Equal([]byte("hello vault")))
+
+		Expect(c.Delete(context.Background(), "k")).To(Succeed())
+		_, err = c.Get(context.Background(), "k")
+		Expect(err).To(Equal(cache.ErrCacheMiss))
+	})
+})
+
+var _ = Describe("Manager", func() {
+	It("issues a certificate on demand and proactively renews it", func() {
+		m := &cache.Manager{
+			Issuer:      issuer,
+			Cache:       cache.NewMemCache(),
+			RenewBefore: 0.1,
+		}
+
+		hello := &tls.ClientHelloInfo{ServerName: "renew.myserver.com"}
+		cert, err := m.GetCertificate(hello)
+		Expect(err).To(Succeed())
+		Expect(cert.Leaf.Subject.CommonName).To(Equal("renew.myserver.com"))
+
+		firstSerial := cert.Leaf.SerialNumber
+
+		Eventually(func() bool {
+			cert, err := m.GetCertificate(hello)
+			Expect(err).To(Succeed())
+			return cert.Leaf.SerialNumber.Cmp(firstSerial) != 0
+		}, "5s", "200ms").Should(BeTrue())
+	})
+})
@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// DirCache implements Cache by storing each entry as a file in the
+// named directory, created with mode 0700 if it doesn't already exist.
+// Modeled directly on golang.org/x/crypto/acme/autocert.DirCache.
+type DirCache string
+
+// Get implements Cache.
+func (d DirCache) Get(ctx context.Context, key string) ([]byte, error) {
+	name := filepath.Join(string(d), key)
+
+	var (
+		data []byte
+		err  error
+		done = make(chan struct{})
+	)
+	go func() {
+		data, err = ioutil.ReadFile(name)
+		close(done)
+	}()
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-done:
+	}
+	if os.IsNotExist(err) {
+		return nil, ErrCacheMiss
+	}
+	return data, err
+}
+
+// Put implements Cache. It writes to a temporary file in the same
+// directory and renames it into place, so a concurrent Get never
+// observes a partially written entry.
+func (d DirCache) Put(ctx context.Context, key string, data []byte) error {
+	if err := os.MkdirAll(string(d), 0700); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- writeTempFile(string(d), key, data)
+	}()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-done:
+		return err
+	}
+}
+
+// Delete implements Cache.
+func (d DirCache) Delete(ctx context.Context, key string) error {
+	name := filepath.Join(string(d), key)
+	var err error
+	done := make(chan struct{})
+	go func() {
+		err = os.Remove(name)
+		close(done)
+	}()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-done:
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func writeTempFile(dir, key string, data []byte) error {
+	f, err := ioutil.TempFile(dir, key+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0600); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), filepath.Join(dir, key))
+}
@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	"github.com/johanbrandhorst/vaulter"
+)
+
+// certEntry is an issued certificate as held by Manager: the parsed
+// leaf, its chain and private key, plus the leaf's validity window used
+// to decide on renewal. encode and decodeCertEntry convert it to and
+// from the single PEM blob stored in a Cache.
+type certEntry struct {
+	leaf       *x509.Certificate
+	chain      []*x509.Certificate
+	privateKey interface{}
+
+	notBefore time.Time
+	notAfter  time.Time
+}
+
+func newCertEntry(cert *vaulter.Certificate) (*certEntry, error) {
+	return &certEntry{
+		leaf:       cert.Leaf,
+		chain:      cert.Chain,
+		privateKey: cert.PrivateKey,
+		notBefore:  cert.Leaf.NotBefore,
+		notAfter:   cert.Leaf.NotAfter,
+	}, nil
+}
+
+// needsRenewal reports whether fraction of the certificate's lifetime
+// has already elapsed.
+func (e *certEntry) needsRenewal(fraction float64) bool {
+	lifetime := e.notAfter.Sub(e.notBefore)
+	renewAt := e.notBefore.Add(time.Duration(float64(lifetime) * fraction))
+	return time.Now().After(renewAt)
+}
+
+func (e *certEntry) tlsCertificate() *tls.Certificate {
+	cert := &tls.Certificate{
+		Certificate: [][]byte{e.leaf.Raw},
+		PrivateKey:  e.privateKey,
+		Leaf:        e.leaf,
+	}
+	for _, c := range e.chain {
+		cert.Certificate = append(cert.Certificate, c.Raw)
+	}
+	return cert
+}
+
+// encode serialises the entry as concatenated PEM blocks: the leaf, each
+// chain certificate, then the private key, in that order.
+func (e *certEntry) encode() []byte {
+	var out []byte
+	out = append(out, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: e.leaf.Raw})...)
+	for _, c := range e.chain {
+		out = append(out, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Raw})...)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(e.privateKey)
+	if err == nil {
+		out = append(out, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})...)
+	}
+	return out
+}
+
+func decodeCertEntry(data []byte) (*certEntry, error) {
+	var entry certEntry
+	rest := data
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		switch block.Type {
+		case "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("cache: parsing cached certificate: %w", err)
+			}
+			if entry.leaf == nil {
+				entry.leaf = cert
+			} else {
+				entry.chain = append(entry.chain, cert)
+			}
+		case "PRIVATE KEY":
+			key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("cache: parsing cached private key: %w", err)
+			}
+			entry.privateKey = key
+		}
+	}
+	if entry.leaf == nil || entry.privateKey == nil {
+		return nil, fmt.Errorf("cache: incomplete cached entry")
+	}
+	entry.notBefore = entry.leaf.NotBefore
+	entry.notAfter = entry.leaf.NotAfter
+	return &entry, nil
+}
@@ -0,0 +1,46 @@
+package cache
+
+import (
+	"context"
+	"sync"
+)
+
+// MemCache is an in-memory Cache. It does not persist across restarts
+// and is mainly useful for tests and single-process deployments that
+// don't need a warm cache after a restart.
+type MemCache struct {
+	mu    sync.RWMutex
+	certs map[string][]byte
+}
+
+// NewMemCache returns an empty MemCache.
+func NewMemCache() *MemCache {
+	return &MemCache{certs: map[string][]byte{}}
+}
+
+// Get implements Cache.
+func (c *MemCache) Get(_ context.Context, key string) ([]byte, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	data, ok := c.certs[key]
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+	return data, nil
+}
+
+// Put implements Cache.
+func (c *MemCache) Put(_ context.Context, key string, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.certs[key] = data
+	return nil
+}
+
+// Delete implements Cache.
+func (c *MemCache) Delete(_ context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.certs, key)
+	return nil
+}
@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// VaultCache implements Cache on top of a Vault KV version 2 secrets
+// engine, so that issued certificates survive restarts without relying
+// on local disk.
+type VaultCache struct {
+	// Client is the Vault client used to read and write cache entries.
+	Client *api.Client
+	// Mount is the path the kv-v2 secrets engine is mounted at, e.g.
+	// "secret".
+	Mount string
+	// Prefix, if set, is prepended to every cache key's path under the
+	// mount, e.g. "vaulter/certs".
+	Prefix string
+}
+
+const dataField = "certificate"
+
+func (v *VaultCache) path(key string) string {
+	if v.Prefix == "" {
+		return key
+	}
+	return v.Prefix + "/" + key
+}
+
+// Get implements Cache.
+func (v *VaultCache) Get(_ context.Context, key string) ([]byte, error) {
+	secret, err := v.Client.Logical().Read(fmt.Sprintf("%s/data/%s", v.Mount, v.path(key)))
+	if err != nil {
+		return nil, fmt.Errorf("cache: reading %q from vault: %w", key, err)
+	}
+	if secret == nil || secret.Data["data"] == nil {
+		return nil, ErrCacheMiss
+	}
+
+	data, ok := secret.Data["data"].(map[string]interface{})
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+	encoded, ok := data[dataField].(string)
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+
+	return base64.StdEncoding.DecodeString(encoded)
+}
+
+// Put implements Cache.
+func (v *VaultCache) Put(_ context.Context, key string, data []byte) error {
+	_, err := v.Client.Logical().Write(fmt.Sprintf("%s/data/%s", v.Mount, v.path(key)), map[string]interface{}{
+		"data": map[string]interface{}{
+			dataField: base64.StdEncoding.EncodeToString(data),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("cache: writing %q to vault: %w", key, err)
+	}
+	return nil
+}
+
+// Delete implements Cache. It removes all versions and metadata for the
+// entry, rather than just soft-deleting the latest version.
+func (v *VaultCache) Delete(_ context.Context, key string) error {
+	_, err := v.Client.Logical().Delete(fmt.Sprintf("%s/metadata/%s", v.Mount, v.path(key)))
+	if err != nil {
+		return fmt.Errorf("cache: deleting %q from vault: %w", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+// Package conformance holds Ginkgo specs exercising the vaulter.Issuer
+// contract, shared between the test suites of backends that implement
+// it so they're all held to the same behavior.
+package conformance
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/johanbrandhorst/vaulter"
+)
+
+// IssuerSpecs registers a Describe block named desc that exercises
+// Issue, Renew and TrustBundle against the vaulter.Issuer returned by
+// newIssuer, requesting certificates for dnsName. Callers assign its
+// result to a package-level "_" var, the same way Ginkgo's own Describe
+// is used at package scope.
+func IssuerSpecs(desc string, newIssuer func() vaulter.Issuer, dnsName string) bool {
+	return Describe(desc, func() {
+		It("issues a certificate for the requested DNS name", func() {
+			cert, err := newIssuer().Issue(vaulter.CertRequest{
+				CommonName: dnsName,
+				DNSNames:   []string{dnsName},
+			})
+			Expect(err).To(Succeed())
+			Expect(cert.Leaf.DNSNames).To(ContainElement(dnsName))
+			Expect(cert.PrivateKey).ToNot(BeNil())
+		})
+
+		It("renews a certificate with a new serial number", func() {
+			issuer := newIssuer()
+			req := vaulter.CertRequest{CommonName: dnsName, DNSNames: []string{dnsName}}
+
+			first, err := issuer.Issue(req)
+			Expect(err).To(Succeed())
+
+			second, err := issuer.Renew(req)
+			Expect(err).To(Succeed())
+
+			Expect(second.Leaf.SerialNumber.Cmp(first.Leaf.SerialNumber)).ToNot(Equal(0))
+		})
+
+		It("returns a non-empty trust bundle", func() {
+			issuer := newIssuer()
+			_, err := issuer.Issue(vaulter.CertRequest{CommonName: dnsName, DNSNames: []string{dnsName}})
+			Expect(err).To(Succeed())
+
+			bundle, err := issuer.TrustBundle()
+			Expect(err).To(Succeed())
+			Expect(bundle).ToNot(BeEmpty())
+		})
+	})
+}
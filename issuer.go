@@ -0,0 +1,61 @@
+// Package vaulter defines the Issuer contract implemented by this
+// module's certificate backends (Vault PKI, ACME, step-ca), so that
+// callers can swap between them without changing call sites.
+package vaulter
+
+import (
+	"crypto"
+	"crypto/x509"
+	"net"
+	"net/url"
+)
+
+// CertRequest describes the certificate to be issued. At least one of
+// CommonName, DNSNames or IPAddresses must be set.
+type CertRequest struct {
+	// CommonName is the subject common name of the certificate.
+	CommonName string
+	// DNSNames, IPAddresses and URIs are added as Subject Alternative
+	// Names.
+	DNSNames    []string
+	IPAddresses []net.IP
+	URIs        []*url.URL
+	// OtherNameUPN, if set, requests a User Principal Name otherName
+	// SAN (OID 1.3.6.1.4.1.311.20.2.3), as used by AD-style PKI roles.
+	// Support is backend-specific: Vault's PKI engine accepts it
+	// directly, while ACME and step-ca have no standard way to request
+	// it and return an error if it's set.
+	OtherNameUPN string
+	// TTL is the requested certificate lifetime, in a backend-specific
+	// duration format. Backends that don't support caller-specified
+	// TTLs ignore it.
+	TTL string
+}
+
+// Certificate is the result of a successful issuance.
+type Certificate struct {
+	// Leaf is the issued end-entity certificate.
+	Leaf *x509.Certificate
+	// Chain contains the intermediate and root certificates returned by
+	// the backend, in the order it returned them.
+	Chain []*x509.Certificate
+	// PrivateKey is the private key generated for Leaf.
+	PrivateKey crypto.Signer
+}
+
+// Issuer issues and renews certificates from a PKI backend. Vault PKI,
+// ACME and step-ca all implement it, so consumers can pick a backend
+// (or mix several, e.g. Vault for internal services and ACME for
+// public-facing ones) without changing call sites.
+type Issuer interface {
+	// Issue requests a new certificate for the identifiers in req.
+	Issue(req CertRequest) (*Certificate, error)
+	// Renew requests a fresh certificate for the same identifiers as a
+	// previous Issue call. Backends without a renewal concept of their
+	// own (Vault PKI, ACME, step-ca's JWK provisioner) treat it as
+	// equivalent to Issue.
+	Renew(req CertRequest) (*Certificate, error)
+	// TrustBundle returns the CA certificate chain that validates
+	// certificates issued by this backend.
+	TrustBundle() ([]*x509.Certificate, error)
+}
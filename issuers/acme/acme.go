@@ -0,0 +1,266 @@
+// Package acme implements certificate issuance and renewal against any
+// RFC 8555 compliant ACME CA, such as Let's Encrypt, step-ca, Boulder, or
+// Vault's own ACME endpoint.
+package acme
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"time"
+
+	"golang.org/x/crypto/acme"
+
+	"github.com/johanbrandhorst/vaulter"
+)
+
+const (
+	// ChallengeHTTP01 identifies the http-01 challenge type.
+	ChallengeHTTP01 = "http-01"
+	// ChallengeTLSALPN01 identifies the tls-alpn-01 challenge type.
+	ChallengeTLSALPN01 = "tls-alpn-01"
+)
+
+// ChallengeSolver proves control of an identifier by presenting the
+// response to a single ACME challenge, and tears it down again once the
+// CA has validated it. Implementations are provided per challenge type,
+// e.g. one that serves /.well-known/acme-challenge/<token> for http-01
+// and one that answers a TLS handshake with the acmeIdentifier extension
+// for tls-alpn-01.
+type ChallengeSolver interface {
+	Present(ctx context.Context, domain, token, keyAuth string) error
+	CleanUp(ctx context.Context, domain, token, keyAuth string) error
+}
+
+// Config configures an ACME Issuer.
+type Config struct {
+	// DirectoryURL is the ACME server's directory endpoint.
+	DirectoryURL string
+	// HTTPClient is used for all requests to the ACME server. Defaults
+	// to http.DefaultClient.
+	HTTPClient *http.Client
+	// AccountKey is the account's signing key. A P-256 key is generated
+	// if nil.
+	AccountKey crypto.Signer
+	// Contact holds contact URIs (e.g. "mailto:admin@example.com")
+	// registered with the account.
+	Contact []string
+	// EABKeyID and EABKey, if set, are used to bind the account to an
+	// external one, as required by CAs that gate issuance behind a
+	// pre-existing relationship (EABKey is the base64url-encoded MAC
+	// key the CA handed out).
+	EABKeyID string
+	EABKey   string
+	// Solvers maps a challenge type (ChallengeHTTP01, ChallengeTLSALPN01)
+	// to the solver used to complete it. Issue picks the first
+	// authorization challenge with a configured solver.
+	Solvers map[string]ChallengeSolver
+	// PollTimeout bounds how long Issue waits for authorization and
+	// order finalization to complete. Defaults to 2 minutes.
+	PollTimeout time.Duration
+}
+
+// Issuer issues and renews certificates from an ACME CA.
+type Issuer struct {
+	client  *acme.Client
+	account *acme.Account
+	solvers map[string]ChallengeSolver
+
+	pollTimeout time.Duration
+
+	lastChain []*x509.Certificate
+}
+
+var _ vaulter.Issuer = (*Issuer)(nil)
+
+// New registers (or re-registers) an ACME account with the CA at
+// cfg.DirectoryURL and returns an Issuer bound to it.
+func New(ctx context.Context, cfg Config) (*Issuer, error) {
+	key := cfg.AccountKey
+	if key == nil {
+		var err error
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("acme: generating account key: %w", err)
+		}
+	}
+
+	client := &acme.Client{
+		Key:          key,
+		DirectoryURL: cfg.DirectoryURL,
+		HTTPClient:   cfg.HTTPClient,
+	}
+
+	acct := &acme.Account{Contact: cfg.Contact}
+	if cfg.EABKeyID != "" {
+		macKey, err := base64.RawURLEncoding.DecodeString(cfg.EABKey)
+		if err != nil {
+			return nil, fmt.Errorf("acme: decoding EAB key: %w", err)
+		}
+		acct.ExternalAccountBinding = &acme.ExternalAccountBinding{
+			KID: cfg.EABKeyID,
+			Key: macKey,
+		}
+	}
+
+	acct, err := client.Register(ctx, acct, acme.AcceptTOS)
+	if err != nil && err != acme.ErrAccountAlreadyExists {
+		return nil, fmt.Errorf("acme: registering account: %w", err)
+	}
+
+	pollTimeout := cfg.PollTimeout
+	if pollTimeout <= 0 {
+		pollTimeout = 2 * time.Minute
+	}
+
+	return &Issuer{
+		client:      client,
+		account:     acct,
+		solvers:     cfg.Solvers,
+		pollTimeout: pollTimeout,
+	}, nil
+}
+
+// Issue requests a new certificate, completing whichever authorized
+// challenge type has a configured solver for each identifier.
+//
+// ACME has no standard way to request a UPN otherName SAN, so
+// req.OtherNameUPN must be empty.
+func (i *Issuer) Issue(req vaulter.CertRequest) (*vaulter.Certificate, error) {
+	if req.OtherNameUPN != "" {
+		return nil, fmt.Errorf("acme: OtherNameUPN is not supported by ACME")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), i.pollTimeout)
+	defer cancel()
+
+	ids := make([]acme.AuthzID, 0, len(req.DNSNames)+len(req.IPAddresses))
+	for _, name := range req.DNSNames {
+		ids = append(ids, acme.AuthzID{Type: "dns", Value: name})
+	}
+	for _, ip := range req.IPAddresses {
+		ids = append(ids, acme.AuthzID{Type: "ip", Value: ip.String()})
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("acme: no identifiers requested")
+	}
+
+	order, err := i.client.AuthorizeOrder(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("acme: creating order: %w", err)
+	}
+
+	for _, authzURL := range order.AuthzURLs {
+		if err := i.completeAuthorization(ctx, authzURL); err != nil {
+			return nil, err
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("acme: generating certificate key: %w", err)
+	}
+
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:     pkix.Name{CommonName: req.CommonName},
+		DNSNames:    req.DNSNames,
+		IPAddresses: req.IPAddresses,
+	}, key)
+	if err != nil {
+		return nil, fmt.Errorf("acme: creating CSR: %w", err)
+	}
+
+	// CreateOrderCert submits the CSR to the order's finalize URL and
+	// blocks until the CA has issued the certificate, polling the order
+	// internally until it leaves the "processing" state.
+	chain, _, err := i.client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return nil, fmt.Errorf("acme: finalizing order: %w", err)
+	}
+
+	leaf, err := x509.ParseCertificate(chain[0])
+	if err != nil {
+		return nil, fmt.Errorf("acme: parsing issued certificate: %w", err)
+	}
+
+	parsedChain := make([]*x509.Certificate, 0, len(chain)-1)
+	for _, der := range chain[1:] {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("acme: parsing chain certificate: %w", err)
+		}
+		parsedChain = append(parsedChain, cert)
+	}
+	i.lastChain = parsedChain
+
+	return &vaulter.Certificate{
+		Leaf:       leaf,
+		Chain:      parsedChain,
+		PrivateKey: key,
+	}, nil
+}
+
+// Renew requests a fresh certificate for the same identifiers. ACME has
+// no notion of renewing an existing order, so Renew is equivalent to
+// calling Issue again.
+func (i *Issuer) Renew(req vaulter.CertRequest) (*vaulter.Certificate, error) {
+	return i.Issue(req)
+}
+
+// TrustBundle returns the chain certificates (excluding the leaf) from
+// the most recently issued certificate.
+func (i *Issuer) TrustBundle() ([]*x509.Certificate, error) {
+	if i.lastChain == nil {
+		return nil, fmt.Errorf("acme: no certificate has been issued yet")
+	}
+	return i.lastChain, nil
+}
+
+func (i *Issuer) completeAuthorization(ctx context.Context, authzURL string) error {
+	authz, err := i.client.GetAuthorization(ctx, authzURL)
+	if err != nil {
+		return fmt.Errorf("acme: fetching authorization: %w", err)
+	}
+	if authz.Status == acme.StatusValid {
+		return nil
+	}
+
+	var chal *acme.Challenge
+	var solver ChallengeSolver
+	for _, c := range authz.Challenges {
+		if s, ok := i.solvers[c.Type]; ok {
+			chal, solver = c, s
+			break
+		}
+	}
+	if chal == nil {
+		return fmt.Errorf("acme: no configured solver for any offered challenge on %s", authz.Identifier.Value)
+	}
+
+	keyAuth, err := i.client.HTTP01ChallengeResponse(chal.Token)
+	if err != nil {
+		return fmt.Errorf("acme: computing key authorization: %w", err)
+	}
+
+	if err := solver.Present(ctx, authz.Identifier.Value, chal.Token, keyAuth); err != nil {
+		return fmt.Errorf("acme: presenting challenge: %w", err)
+	}
+	defer solver.CleanUp(ctx, authz.Identifier.Value, chal.Token, keyAuth)
+
+	if _, err := i.client.Accept(ctx, chal); err != nil {
+		return fmt.Errorf("acme: accepting challenge: %w", err)
+	}
+
+	if _, err := i.client.WaitAuthorization(ctx, authzURL); err != nil {
+		return fmt.Errorf("acme: waiting for authorization: %w", err)
+	}
+
+	return nil
+}
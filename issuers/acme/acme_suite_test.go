@@ -0,0 +1,112 @@
+package acme_test
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/ory/dockertest"
+	"github.com/ory/dockertest/docker"
+
+	"github.com/johanbrandhorst/vaulter"
+	"github.com/johanbrandhorst/vaulter/internal/conformance"
+	"github.com/johanbrandhorst/vaulter/issuers/acme"
+)
+
+func TestACME(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "ACME Suite")
+}
+
+var (
+	pool     *dockertest.Pool
+	resource *dockertest.Resource
+
+	directoryURL string
+	httpClient   *http.Client
+)
+
+var _ = BeforeSuite(func() {
+	var err error
+	pool, err = dockertest.NewPool("")
+	Expect(err).To(Succeed())
+
+	pool.MaxWait = time.Second * 30
+
+	By("Starting the Pebble container", func() {
+		repo := "letsencrypt/pebble"
+		version := "latest"
+		img := repo + ":" + version
+		_, err = pool.Client.InspectImage(img)
+		if err != nil {
+			Expect(pool.Client.PullImage(docker.PullImageOptions{
+				Repository:   repo,
+				Tag:          version,
+				OutputStream: GinkgoWriter,
+			}, docker.AuthConfiguration{})).To(Succeed())
+		}
+
+		resource, err = pool.RunWithOptions(&dockertest.RunOptions{
+			Repository: repo,
+			Tag:        version,
+			Env: []string{
+				// Skip real challenge validation: the suite cannot make
+				// itself reachable from inside the container network, so
+				// it proves the client/server protocol plumbing instead
+				// of live challenge delivery.
+				"PEBBLE_VA_ALWAYS_VALID=1",
+				"PEBBLE_WFE_NONCEREJECT=0",
+			},
+			ExposedPorts: []string{"14000/tcp"},
+		})
+		Expect(err).To(Succeed())
+
+		directoryURL = "https://localhost:" + resource.GetPort("14000/tcp") + "/dir"
+
+		// Pebble serves a self-signed certificate; the test fixture
+		// trusts it the same way it would trust a real CA's chain once
+		// deployed.
+		httpClient = &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			},
+		}
+
+		Expect(pool.Retry(func() error {
+			_, err := httpClient.Get(directoryURL)
+			return err
+		})).To(Succeed())
+	})
+})
+
+var _ = AfterSuite(func() {
+	if resource != nil {
+		Expect(pool.Purge(resource)).To(Succeed())
+	}
+})
+
+// noopSolver satisfies acme.ChallengeSolver without serving anything:
+// Pebble is started with PEBBLE_VA_ALWAYS_VALID=1, so the suite cannot
+// (and doesn't need to) make a real challenge response reachable from
+// inside the container network, only offer a solver for a challenge
+// type Pebble presents so completeAuthorization has one to pick.
+type noopSolver struct{}
+
+func (noopSolver) Present(ctx context.Context, domain, token, keyAuth string) error { return nil }
+func (noopSolver) CleanUp(ctx context.Context, domain, token, keyAuth string) error { return nil }
+
+var _ = conformance.IssuerSpecs("ACME", func() vaulter.Issuer {
+	issuer, err := acme.New(context.Background(), acme.Config{
+		DirectoryURL: directoryURL,
+		HTTPClient:   httpClient,
+		Solvers: map[string]acme.ChallengeSolver{
+			acme.ChallengeHTTP01: noopSolver{},
+		},
+	})
+	Expect(err).To(Succeed())
+	return issuer
+}, "conformance.example.com")
@@ -0,0 +1,218 @@
+// Package stepca implements certificate issuance and renewal against a
+// smallstep step-ca server, authenticating with a JWK provisioner.
+package stepca
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	smallstepapi "github.com/smallstep/certificates/api"
+	"github.com/smallstep/certificates/ca"
+	jose "gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+
+	"github.com/johanbrandhorst/vaulter"
+)
+
+// Config configures a step-ca issuer using the JWK provisioner flow: the
+// same one step-cli uses when it prompts for a provisioner password.
+type Config struct {
+	// CAURL is the step-ca server's address, e.g. "https://ca.internal".
+	CAURL string
+	// RootFingerprint is the SHA-256 fingerprint of the CA's root
+	// certificate, used to bootstrap trust in the same way `step ca
+	// bootstrap` does.
+	RootFingerprint string
+	// Provisioner is the JWK provisioner's name, sent as the one-time
+	// token's "iss" claim. The token's "kid" header is taken from the
+	// decrypted provisioner key.
+	Provisioner string
+	// EncryptedKey is the provisioner's encrypted private key, as
+	// printed by `step ca provisioner list`.
+	EncryptedKey string
+	// Password decrypts EncryptedKey.
+	Password []byte
+	// OTTLifetime bounds how long a one-time token minted for a single
+	// Issue/Renew call is valid for. Defaults to 5 minutes.
+	OTTLifetime time.Duration
+}
+
+// Issuer issues and renews certificates from a step-ca server. It
+// implements vaulter.Issuer.
+type Issuer struct {
+	client      *ca.Client
+	caURL       string
+	provisioner string
+	signer      jose.Signer
+	ottLifetime time.Duration
+
+	lastRoots []*x509.Certificate
+}
+
+var _ vaulter.Issuer = (*Issuer)(nil)
+
+// New bootstraps trust in the CA at cfg.CAURL and decrypts the JWK
+// provisioner's private key, ready to mint one-time tokens for Issue
+// and Renew.
+func New(cfg Config) (*Issuer, error) {
+	client, err := ca.NewClient(cfg.CAURL, ca.WithRootSHA256(cfg.RootFingerprint))
+	if err != nil {
+		return nil, fmt.Errorf("stepca: creating client: %w", err)
+	}
+
+	encrypted, err := jose.ParseEncrypted(cfg.EncryptedKey)
+	if err != nil {
+		return nil, fmt.Errorf("stepca: parsing encrypted provisioner key: %w", err)
+	}
+	decrypted, err := encrypted.Decrypt(cfg.Password)
+	if err != nil {
+		return nil, fmt.Errorf("stepca: decrypting provisioner key: %w", err)
+	}
+	var jwk jose.JSONWebKey
+	if err := jwk.UnmarshalJSON(decrypted); err != nil {
+		return nil, fmt.Errorf("stepca: parsing provisioner JWK: %w", err)
+	}
+
+	// step-ca's JWK provisioner picks the provisioner to validate against
+	// by the token's "kid" header, so it must be set explicitly here;
+	// jose.NewSigner doesn't derive it from the signing key on its own.
+	opts := (&jose.SignerOptions{}).WithHeader("kid", jwk.KeyID)
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: jwk.Key}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("stepca: creating OTT signer: %w", err)
+	}
+
+	ottLifetime := cfg.OTTLifetime
+	if ottLifetime <= 0 {
+		ottLifetime = 5 * time.Minute
+	}
+
+	return &Issuer{
+		client:      client,
+		caURL:       cfg.CAURL,
+		provisioner: cfg.Provisioner,
+		signer:      signer,
+		ottLifetime: ottLifetime,
+	}, nil
+}
+
+// Issue generates a private key and a one-time token from the JWK
+// provisioner, and uses both to request a certificate from step-ca.
+//
+// step-ca's JWK provisioner has no standard way to request a UPN
+// otherName SAN, so req.OtherNameUPN must be empty.
+func (i *Issuer) Issue(req vaulter.CertRequest) (*vaulter.Certificate, error) {
+	if req.OtherNameUPN != "" {
+		return nil, fmt.Errorf("stepca: OtherNameUPN is not supported")
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("stepca: generating key: %w", err)
+	}
+
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:     pkix.Name{CommonName: req.CommonName},
+		DNSNames:    req.DNSNames,
+		IPAddresses: req.IPAddresses,
+		URIs:        req.URIs,
+	}, key)
+	if err != nil {
+		return nil, fmt.Errorf("stepca: creating CSR: %w", err)
+	}
+	parsedCSR, err := x509.ParseCertificateRequest(csr)
+	if err != nil {
+		return nil, fmt.Errorf("stepca: parsing CSR: %w", err)
+	}
+
+	ott, err := i.signOTT(req.CommonName, parsedCSR)
+	if err != nil {
+		return nil, fmt.Errorf("stepca: minting one-time token: %w", err)
+	}
+
+	resp, err := i.client.Sign(&smallstepapi.SignRequest{
+		CsrPEM: smallstepapi.CertificateRequest{CertificateRequest: parsedCSR},
+		OTT:    ott,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("stepca: signing certificate: %w", err)
+	}
+
+	chain := make([]*x509.Certificate, 0, len(resp.CertChainPEM))
+	for _, c := range resp.CertChainPEM {
+		chain = append(chain, c.Certificate)
+	}
+	if resp.CaPEM.Certificate != nil {
+		i.lastRoots = []*x509.Certificate{resp.CaPEM.Certificate}
+	}
+
+	return &vaulter.Certificate{
+		Leaf:       resp.ServerPEM.Certificate,
+		Chain:      chain,
+		PrivateKey: key,
+	}, nil
+}
+
+// Renew requests a fresh certificate with a new one-time token. step-ca
+// also supports renewing over an mTLS connection established with the
+// previous certificate, but that requires holding the connection open
+// across calls, so Renew is equivalent to calling Issue again here.
+func (i *Issuer) Renew(req vaulter.CertRequest) (*vaulter.Certificate, error) {
+	return i.Issue(req)
+}
+
+// TrustBundle returns the CA certificate observed in the most recent
+// Issue or Renew response. Before any response has carried one, it
+// fetches the CA's root certificates from the server instead.
+func (i *Issuer) TrustBundle() ([]*x509.Certificate, error) {
+	if i.lastRoots == nil {
+		roots, err := i.client.Roots()
+		if err != nil {
+			return nil, fmt.Errorf("stepca: fetching trust bundle: %w", err)
+		}
+		for _, c := range roots.Certificates {
+			i.lastRoots = append(i.lastRoots, c.Certificate)
+		}
+	}
+	return i.lastRoots, nil
+}
+
+// signOTT mints a JWK provisioner one-time token authorizing the CSR's
+// subject and SANs, the same flow `step ca token` drives interactively.
+func (i *Issuer) signOTT(subject string, csr *x509.CertificateRequest) (string, error) {
+	now := time.Now()
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return "", fmt.Errorf("generating token id: %w", err)
+	}
+
+	claims := jwt.Claims{
+		Issuer:    i.provisioner,
+		Subject:   subject,
+		Audience:  jwt.Audience{i.caURL + "/1.0/sign"},
+		Expiry:    jwt.NewNumericDate(now.Add(i.ottLifetime)),
+		NotBefore: jwt.NewNumericDate(now),
+		IssuedAt:  jwt.NewNumericDate(now),
+		ID:        id.String(),
+	}
+
+	sans := make([]string, 0, len(csr.DNSNames)+len(csr.IPAddresses)+len(csr.URIs))
+	sans = append(sans, csr.DNSNames...)
+	for _, ip := range csr.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	for _, u := range csr.URIs {
+		sans = append(sans, u.String())
+	}
+
+	return jwt.Signed(i.signer).
+		Claims(claims).
+		Claims(map[string]interface{}{"sans": sans}).
+		CompactSerialize()
+}
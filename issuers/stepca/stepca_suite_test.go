@@ -0,0 +1,142 @@
+package stepca_test
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/ory/dockertest"
+	"github.com/ory/dockertest/docker"
+
+	"github.com/johanbrandhorst/vaulter"
+	"github.com/johanbrandhorst/vaulter/internal/conformance"
+	"github.com/johanbrandhorst/vaulter/issuers/stepca"
+)
+
+func TestStepCA(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "step-ca Suite")
+}
+
+const (
+	provisionerName = "admin"
+	password        = "mysecretpassword"
+)
+
+var (
+	pool     *dockertest.Pool
+	resource *dockertest.Resource
+
+	caURL           string
+	rootFingerprint string
+	encryptedKey    string
+
+	insecureClient = &http.Client{
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+	}
+)
+
+var _ = BeforeSuite(func() {
+	var err error
+	pool, err = dockertest.NewPool("")
+	Expect(err).To(Succeed())
+	pool.MaxWait = time.Second * 30
+
+	By("Starting the step-ca container", func() {
+		repo := "smallstep/step-ca"
+		version := "latest"
+		img := repo + ":" + version
+		_, err = pool.Client.InspectImage(img)
+		if err != nil {
+			Expect(pool.Client.PullImage(docker.PullImageOptions{
+				Repository:   repo,
+				Tag:          version,
+				OutputStream: GinkgoWriter,
+			}, docker.AuthConfiguration{})).To(Succeed())
+		}
+
+		resource, err = pool.RunWithOptions(&dockertest.RunOptions{
+			Repository: repo,
+			Tag:        version,
+			Env: []string{
+				"DOCKER_STEPCA_INIT_NAME=vaulter-test",
+				"DOCKER_STEPCA_INIT_DNS_NAMES=localhost",
+				"DOCKER_STEPCA_INIT_PASSWORD=" + password,
+				"DOCKER_STEPCA_INIT_PROVISIONER_NAME=" + provisionerName,
+			},
+			ExposedPorts: []string{"9000/tcp"},
+		})
+		Expect(err).To(Succeed())
+
+		caURL = "https://localhost:" + resource.GetPort("9000/tcp")
+
+		Expect(pool.Retry(func() error {
+			_, err := insecureClient.Get(caURL + "/health")
+			return err
+		})).To(Succeed())
+	})
+
+	By("Fetching the root fingerprint and provisioner key", func() {
+		resp, err := insecureClient.Get(caURL + "/roots")
+		Expect(err).To(Succeed())
+		defer resp.Body.Close()
+
+		var roots struct {
+			Crts []string `json:"crts"`
+		}
+		Expect(json.NewDecoder(resp.Body).Decode(&roots)).To(Succeed())
+		Expect(roots.Crts).ToNot(BeEmpty())
+
+		// ca.WithRootSHA256 fingerprints the root certificate's DER
+		// bytes, not the PEM text, so decode it first.
+		block, _ := pem.Decode([]byte(roots.Crts[0]))
+		Expect(block).ToNot(BeNil())
+		sum := sha256.Sum256(block.Bytes)
+		rootFingerprint = hex.EncodeToString(sum[:])
+
+		resp, err = insecureClient.Get(caURL + "/provisioners")
+		Expect(err).To(Succeed())
+		defer resp.Body.Close()
+
+		var list struct {
+			Provisioners []struct {
+				Name         string `json:"name"`
+				EncryptedKey string `json:"encryptedKey"`
+			} `json:"provisioners"`
+		}
+		Expect(json.NewDecoder(resp.Body).Decode(&list)).To(Succeed())
+
+		for _, p := range list.Provisioners {
+			if p.Name == provisionerName {
+				encryptedKey = p.EncryptedKey
+			}
+		}
+		Expect(encryptedKey).ToNot(BeEmpty(), fmt.Sprintf("no provisioner named %q", provisionerName))
+	})
+})
+
+var _ = AfterSuite(func() {
+	if resource != nil {
+		Expect(pool.Purge(resource)).To(Succeed())
+	}
+})
+
+var _ = conformance.IssuerSpecs("step-ca", func() vaulter.Issuer {
+	issuer, err := stepca.New(stepca.Config{
+		CAURL:           caURL,
+		RootFingerprint: rootFingerprint,
+		Provisioner:     provisionerName,
+		EncryptedKey:    encryptedKey,
+		Password:        []byte(password),
+	})
+	Expect(err).To(Succeed())
+	return issuer
+}, "conformance.step.internal")
@@ -0,0 +1,219 @@
+// Package vault implements certificate issuance and renewal against
+// Vault's PKI secrets engine.
+package vault
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/hashicorp/vault/api"
+
+	"github.com/johanbrandhorst/vaulter"
+)
+
+// DefaultMount is the mount path used when Config.Mount is empty.
+const DefaultMount = "pki"
+
+// Config configures a Vault PKI issuer.
+type Config struct {
+	// URL is the address of the Vault server.
+	URL *url.URL
+	// Token is the Vault token used to authenticate requests. Callers
+	// that need token renewal should use the auth package instead and
+	// pass the resulting api.Client via Client.
+	Token string
+	// Client, if set, is used instead of constructing a client from URL,
+	// Token and CertPool. This allows callers to plug in their own
+	// authentication and renewal strategy.
+	Client *api.Client
+	// CertPool is used to validate the Vault server's TLS certificate.
+	// Ignored if Client is set.
+	CertPool *x509.CertPool
+	// Mount is the path the PKI secrets engine is mounted at. Defaults
+	// to DefaultMount.
+	Mount string
+	// Role is the PKI role used to issue and sign certificates.
+	Role string
+}
+
+// Issuer issues and renews certificates from a Vault PKI mount. It
+// implements vaulter.Issuer.
+type Issuer struct {
+	client *api.Client
+	mount  string
+	role   string
+}
+
+var _ vaulter.Issuer = (*Issuer)(nil)
+
+// New constructs an Issuer from the given Config.
+func New(cfg Config) (*Issuer, error) {
+	cli := cfg.Client
+	if cli == nil {
+		apiCfg := api.DefaultConfig()
+		if cfg.URL != nil {
+			apiCfg.Address = cfg.URL.String()
+		}
+		if cfg.CertPool != nil {
+			apiCfg.HttpClient = &http.Client{
+				Transport: &http.Transport{
+					TLSClientConfig: &tls.Config{RootCAs: cfg.CertPool},
+				},
+			}
+		}
+		var err error
+		cli, err = api.NewClient(apiCfg)
+		if err != nil {
+			return nil, fmt.Errorf("vault: creating client: %w", err)
+		}
+		cli.SetToken(cfg.Token)
+	}
+
+	mount := cfg.Mount
+	if mount == "" {
+		mount = DefaultMount
+	}
+
+	return &Issuer{
+		client: cli,
+		mount:  mount,
+		role:   cfg.Role,
+	}, nil
+}
+
+// Issue generates a private key and requests a certificate for it from
+// Vault's PKI role.
+func (i *Issuer) Issue(req vaulter.CertRequest) (*vaulter.Certificate, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("vault: generating key: %w", err)
+	}
+
+	data := map[string]interface{}{
+		"common_name":        req.CommonName,
+		"private_key_format": "pkcs8",
+	}
+	if len(req.DNSNames) > 0 {
+		data["alt_names"] = joinStrings(req.DNSNames)
+	}
+	if len(req.IPAddresses) > 0 {
+		ips := make([]string, len(req.IPAddresses))
+		for idx, ip := range req.IPAddresses {
+			ips[idx] = ip.String()
+		}
+		data["ip_sans"] = joinStrings(ips)
+	}
+	if len(req.URIs) > 0 {
+		uris := make([]string, len(req.URIs))
+		for idx, u := range req.URIs {
+			uris[idx] = u.String()
+		}
+		data["uri_sans"] = joinStrings(uris)
+	}
+	if req.OtherNameUPN != "" {
+		// Vault's PKI engine accepts arbitrary otherName SANs via
+		// other_sans, independent of what the CSR itself contains, as
+		// long as the role's allowed_other_sans permits the OID. This
+		// is how the UPN otherName (1.3.6.1.4.1.311.20.2.3) used by
+		// AD-style roles is requested.
+		data["other_sans"] = "1.3.6.1.4.1.311.20.2.3;UTF8:" + req.OtherNameUPN
+	}
+	if req.TTL != "" {
+		data["ttl"] = req.TTL
+	}
+
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: req.CommonName},
+	}, key)
+	if err != nil {
+		return nil, fmt.Errorf("vault: creating CSR: %w", err)
+	}
+	data["csr"] = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrDER}))
+
+	secret, err := i.client.Logical().Write(fmt.Sprintf("%s/sign/%s", i.mount, i.role), data)
+	if err != nil {
+		return nil, fmt.Errorf("vault: signing certificate: %w", err)
+	}
+
+	leafPEM, _ := secret.Data["certificate"].(string)
+	leaf, err := parsePEMCertificate(leafPEM)
+	if err != nil {
+		return nil, fmt.Errorf("vault: parsing issued certificate: %w", err)
+	}
+
+	var chain []*x509.Certificate
+	if cas, ok := secret.Data["ca_chain"].([]interface{}); ok {
+		for _, c := range cas {
+			cert, err := parsePEMCertificate(fmt.Sprint(c))
+			if err != nil {
+				return nil, fmt.Errorf("vault: parsing chain certificate: %w", err)
+			}
+			chain = append(chain, cert)
+		}
+	}
+
+	return &vaulter.Certificate{
+		Leaf:       leaf,
+		Chain:      chain,
+		PrivateKey: key,
+	}, nil
+}
+
+// Renew issues a fresh certificate using the same parameters as the
+// original request. Vault's PKI backend has no notion of renewing an
+// existing lease, so Renew is equivalent to calling Issue again.
+func (i *Issuer) Renew(req vaulter.CertRequest) (*vaulter.Certificate, error) {
+	return i.Issue(req)
+}
+
+// TrustBundle returns the CA certificate chain configured on the PKI
+// mount, suitable for validating certificates it issues.
+func (i *Issuer) TrustBundle() ([]*x509.Certificate, error) {
+	secret, err := i.client.Logical().Read(fmt.Sprintf("%s/cert/ca_chain", i.mount))
+	if err != nil {
+		return nil, fmt.Errorf("vault: reading trust bundle: %w", err)
+	}
+	if secret == nil {
+		return nil, fmt.Errorf("vault: no trust bundle found at mount %q", i.mount)
+	}
+
+	bundlePEM, _ := secret.Data["certificate"].(string)
+	var out []*x509.Certificate
+	rest := []byte(bundlePEM)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("vault: parsing trust bundle: %w", err)
+		}
+		out = append(out, cert)
+	}
+	return out, nil
+}
+
+func parsePEMCertificate(s string) (*x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(s))
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func joinStrings(ss []string) string {
+	out := ss[0]
+	for _, s := range ss[1:] {
+		out += "," + s
+	}
+	return out
+}
@@ -23,6 +23,10 @@ import (
 	. "github.com/onsi/gomega"
 	"github.com/ory/dockertest"
 	"github.com/ory/dockertest/docker"
+
+	"github.com/johanbrandhorst/vaulter"
+	"github.com/johanbrandhorst/vaulter/internal/conformance"
+	"github.com/johanbrandhorst/vaulter/issuers/vault"
 )
 
 func TestVault(t *testing.T) {
@@ -264,6 +268,16 @@ var _ = AfterSuite(func() {
 	Expect(pool.Purge(resource)).To(Succeed())
 })
 
+var _ = conformance.IssuerSpecs("Vault", func() vaulter.Issuer {
+	issuer, err := vault.New(vault.Config{
+		URL:   vaultConf.URL,
+		Token: vaultConf.Token,
+		Role:  vaultConf.Role,
+	})
+	Expect(err).To(Succeed())
+	return issuer
+}, "conformance.myserver.com")
+
 func generateCertAndKey(SAN string, IPSAN net.IP) ([]byte, []byte, error) {
 	priv, err := rsa.GenerateKey(rand.Reader, 2048)
 	if err != nil {
@@ -0,0 +1,214 @@
+// Package verify checks whether certificates issued by a Vault PKI
+// mount have been revoked, using either a periodically refreshed CRL or
+// an on-demand OCSP request.
+package verify
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"golang.org/x/crypto/ocsp"
+)
+
+// DefaultRefreshInterval is used when Config.RefreshInterval is zero.
+const DefaultRefreshInterval = 5 * time.Minute
+
+// Config configures a Verifier.
+type Config struct {
+	// Client is the Vault client used to fetch CRLs and issue OCSP
+	// requests. The mount's crl and ocsp paths are unauthenticated in
+	// Vault, but the same client used for issuance is reused for
+	// simplicity.
+	Client *api.Client
+	// Mount is the path the PKI secrets engine is mounted at.
+	Mount string
+	// RefreshInterval controls how often the background goroutine
+	// refetches the CRL. Defaults to DefaultRefreshInterval.
+	RefreshInterval time.Duration
+}
+
+// Verifier caches a Vault PKI mount's CRL in memory and exposes revoked
+// status lookups for certificates issued by that mount.
+type Verifier struct {
+	client *api.Client
+	mount  string
+	period time.Duration
+
+	mu      sync.RWMutex
+	crl     *x509.RevocationList
+	fetched time.Time
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewVerifier constructs a Verifier and performs an initial CRL fetch.
+// Call Close to stop the background refresher.
+func NewVerifier(cfg Config) (*Verifier, error) {
+	period := cfg.RefreshInterval
+	if period <= 0 {
+		period = DefaultRefreshInterval
+	}
+
+	v := &Verifier{
+		client: cfg.Client,
+		mount:  cfg.Mount,
+		period: period,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	if err := v.refresh(); err != nil {
+		return nil, fmt.Errorf("verify: initial CRL fetch: %w", err)
+	}
+
+	go v.refreshLoop()
+
+	return v, nil
+}
+
+// Close stops the background refresh goroutine.
+func (v *Verifier) Close() error {
+	close(v.stop)
+	<-v.done
+	return nil
+}
+
+// RotateIssuer drops the cached CRL, forcing the next IsRevoked call to
+// block on a fresh fetch. Callers should invoke this after rotating the
+// mount's issuing CA so that stale entries signed by the previous CA are
+// never consulted.
+func (v *Verifier) RotateIssuer() {
+	v.mu.Lock()
+	v.crl = nil
+	v.fetched = time.Time{}
+	v.mu.Unlock()
+}
+
+// IsRevoked reports whether cert appears on the cached CRL. The CRL is
+// refreshed in the background at Config.RefreshInterval; call RotateIssuer
+// after a CA rotation to avoid consulting a CRL signed by the old issuer.
+func (v *Verifier) IsRevoked(cert *x509.Certificate) (bool, error) {
+	v.mu.RLock()
+	crl := v.crl
+	v.mu.RUnlock()
+
+	if crl == nil {
+		if err := v.refresh(); err != nil {
+			return false, fmt.Errorf("verify: fetching CRL: %w", err)
+		}
+		v.mu.RLock()
+		crl = v.crl
+		v.mu.RUnlock()
+	}
+
+	for _, entry := range crl.RevokedCertificateEntries {
+		if entry.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (v *Verifier) refreshLoop() {
+	defer close(v.done)
+
+	t := time.NewTicker(v.period)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-v.stop:
+			return
+		case <-t.C:
+			// Best-effort: keep serving the previous CRL if a refresh
+			// fails, rather than tearing down the cache.
+			_ = v.refresh()
+		}
+	}
+}
+
+func (v *Verifier) refresh() error {
+	der, err := v.fetchCRL()
+	if err != nil {
+		return err
+	}
+	crl, err := x509.ParseRevocationList(der)
+	if err != nil {
+		return fmt.Errorf("parsing CRL: %w", err)
+	}
+
+	v.mu.Lock()
+	v.crl = crl
+	v.fetched = time.Now()
+	v.mu.Unlock()
+
+	return nil
+}
+
+// fetchCRL retrieves the DER-encoded CRL from the mount's unauthenticated
+// /crl endpoint, falling back to the PEM-encoded /crl/pem endpoint.
+func (v *Verifier) fetchCRL() ([]byte, error) {
+	der, err := v.rawRequest(v.mount + "/crl")
+	if err == nil {
+		return der, nil
+	}
+
+	pemBytes, pemErr := v.rawRequest(v.mount + "/crl/pem")
+	if pemErr != nil {
+		return nil, fmt.Errorf("fetching DER CRL: %w; fetching PEM CRL: %w", err, pemErr)
+	}
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block in CRL response")
+	}
+	return block.Bytes, nil
+}
+
+func (v *Verifier) rawRequest(path string) ([]byte, error) {
+	req := v.client.NewRequest(http.MethodGet, "/v1/"+path)
+	resp, err := v.client.RawRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return ioutil.ReadAll(resp.Body)
+}
+
+// CheckOCSP makes an OCSP request for cert to the mount's /ocsp
+// responder and verifies the response signature against issuer before
+// reporting whether cert is revoked. The request itself is not signed.
+func (v *Verifier) CheckOCSP(cert, issuer *x509.Certificate) (bool, error) {
+	reqDER, err := ocsp.CreateRequest(cert, issuer, nil)
+	if err != nil {
+		return false, fmt.Errorf("verify: creating OCSP request: %w", err)
+	}
+
+	req := v.client.NewRequest(http.MethodPost, "/v1/"+v.mount+"/ocsp")
+	req.Headers.Set("Content-Type", "application/ocsp-request")
+	req.BodyBytes = reqDER
+
+	httpResp, err := v.client.RawRequest(req)
+	if err != nil {
+		return false, fmt.Errorf("verify: sending OCSP request: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	respDER, err := ioutil.ReadAll(httpResp.Body)
+	if err != nil {
+		return false, fmt.Errorf("verify: reading OCSP response: %w", err)
+	}
+
+	resp, err := ocsp.ParseResponseForCert(respDER, cert, issuer)
+	if err != nil {
+		return false, fmt.Errorf("verify: verifying OCSP response: %w", err)
+	}
+
+	return resp.Status == ocsp.Revoked, nil
+}
@@ -0,0 +1,150 @@
+package verify_test
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/ory/dockertest"
+	"github.com/ory/dockertest/docker"
+
+	"github.com/johanbrandhorst/vaulter/verify"
+)
+
+func TestVerify(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Verify Suite")
+}
+
+const role = "test"
+
+var (
+	pool     *dockertest.Pool
+	resource *dockertest.Resource
+
+	vaultClient *api.Client
+)
+
+var _ = BeforeSuite(func() {
+	host := "localhost"
+	if os.Getenv("DOCKER_HOST") != "" {
+		u, err := url.Parse(os.Getenv("DOCKER_HOST"))
+		Expect(err).To(Succeed())
+		host, _, err = net.SplitHostPort(u.Host)
+		Expect(err).To(Succeed())
+	}
+
+	var err error
+	pool, err = dockertest.NewPool("")
+	Expect(err).To(Succeed())
+	pool.MaxWait = time.Second * 10
+
+	By("Starting the Vault container", func() {
+		token := "mysecrettoken"
+
+		repo := "vault"
+		version := "1.0.0"
+		img := repo + ":" + version
+		_, err = pool.Client.InspectImage(img)
+		if err != nil {
+			Expect(pool.Client.PullImage(docker.PullImageOptions{
+				Repository:   repo,
+				Tag:          version,
+				OutputStream: GinkgoWriter,
+			}, docker.AuthConfiguration{})).To(Succeed())
+		}
+
+		resource, err = pool.RunWithOptions(&dockertest.RunOptions{
+			Repository: repo,
+			Tag:        version,
+			Env:        []string{"VAULT_DEV_ROOT_TOKEN_ID=" + token},
+			ExposedPorts: []string{"8200/tcp"},
+		})
+		Expect(err).To(Succeed())
+
+		conf := api.DefaultConfig()
+		conf.Address = "http://" + net.JoinHostPort(host, resource.GetPort("8200/tcp"))
+		vaultClient, err = api.NewClient(conf)
+		Expect(err).To(Succeed())
+		vaultClient.SetToken(token)
+
+		Expect(pool.Retry(func() error {
+			_, err := vaultClient.Sys().Health()
+			return err
+		})).To(Succeed())
+
+		Expect(vaultClient.Sys().Mount("pki", &api.MountInput{
+			Type:   "pki",
+			Config: api.MountConfigInput{MaxLeaseTTL: "87600h"},
+		})).To(Succeed())
+		_, err = vaultClient.Logical().Write("pki/root/generate/internal", map[string]interface{}{
+			"ttl":         "87600h",
+			"common_name": "verify-suite",
+		})
+		Expect(err).To(Succeed())
+		_, err = vaultClient.Logical().Write("pki/roles/"+role, map[string]interface{}{
+			"allowed_domains":  "myserver.com",
+			"allow_subdomains": true,
+		})
+		Expect(err).To(Succeed())
+	})
+})
+
+var _ = AfterSuite(func() {
+	if resource != nil {
+		Expect(pool.Purge(resource)).To(Succeed())
+	}
+})
+
+var _ = Describe("Verifier", func() {
+	It("detects a revoked certificate within one refresh cycle", func() {
+		secret, err := vaultClient.Logical().Write("pki/issue/"+role, map[string]interface{}{
+			"common_name": "revoke-me.myserver.com",
+		})
+		Expect(err).To(Succeed())
+		serial := secret.Data["serial_number"].(string)
+
+		v, err := verify.NewVerifier(verify.Config{
+			Client:          vaultClient,
+			Mount:           "pki",
+			RefreshInterval: 500 * time.Millisecond,
+		})
+		Expect(err).To(Succeed())
+		defer v.Close()
+
+		cert, err := certificateFromIssueResponse(secret)
+		Expect(err).To(Succeed())
+
+		revoked, err := v.IsRevoked(cert)
+		Expect(err).To(Succeed())
+		Expect(revoked).To(BeFalse())
+
+		_, err = vaultClient.Logical().Write("pki/revoke", map[string]interface{}{
+			"serial_number": serial,
+		})
+		Expect(err).To(Succeed())
+
+		Eventually(func() bool {
+			revoked, err := v.IsRevoked(cert)
+			Expect(err).To(Succeed())
+			return revoked
+		}, "2s", "100ms").Should(BeTrue())
+	})
+})
+
+func certificateFromIssueResponse(secret *api.Secret) (*x509.Certificate, error) {
+	certPEM, _ := secret.Data["certificate"].(string)
+	block, _ := pem.Decode([]byte(certPEM))
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block in issue response")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}